@@ -0,0 +1,151 @@
+package gendoc
+
+import (
+	"regexp"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/pseudomuto/protokit"
+)
+
+// filterProtos is the two-stage proto filter: first at the file level (the historical ExcludePatterns behavior,
+// plus an IncludePatterns allowlist), then at the descriptor level, dropping any message/enum/service/method/field
+// whose fully-qualified name matches ExcludeSymbols or that carries `[deprecated = true]`.
+func filterProtos(fds []*protokit.FileDescriptor, options *Options) []*protokit.FileDescriptor {
+	fds = filterFiles(fds, options.IncludePatterns, options.ExcludePatterns)
+
+	if len(options.ExcludeSymbols) == 0 && !options.ExcludeDeprecated {
+		return fds
+	}
+
+	for _, fd := range fds {
+		filterSymbols(fd, options)
+	}
+
+	return fds
+}
+
+// filterFiles keeps a file when it matches at least one IncludePatterns entry (or IncludePatterns is empty) and
+// matches none of excludePatterns. This is excludeUnwantedProtos generalized with an include allowlist.
+func filterFiles(fds []*protokit.FileDescriptor, includePatterns, excludePatterns []*regexp.Regexp) []*protokit.FileDescriptor {
+	descs := make([]*protokit.FileDescriptor, 0)
+
+OUTER:
+	for _, d := range fds {
+		for _, p := range excludePatterns {
+			if p.MatchString(d.GetName()) {
+				continue OUTER
+			}
+		}
+
+		if len(includePatterns) > 0 {
+			included := false
+			for _, p := range includePatterns {
+				if p.MatchString(d.GetName()) {
+					included = true
+					break
+				}
+			}
+			if !included {
+				continue OUTER
+			}
+		}
+
+		descs = append(descs, d)
+	}
+
+	return descs
+}
+
+// filterSymbols drops messages, enums, services, methods and fields whose fully-qualified name matches
+// options.ExcludeSymbols, or that are marked `[deprecated = true]` when options.ExcludeDeprecated is set. It
+// mutates fd in place, recursing into nested messages and enums so a symbol buried inside another message is
+// filtered the same as a top-level one.
+func filterSymbols(fd *protokit.FileDescriptor, options *Options) {
+	excluded := func(fqName string, deprecated bool) bool {
+		if options.ExcludeDeprecated && deprecated {
+			return true
+		}
+		for _, p := range options.ExcludeSymbols {
+			if p.MatchString(fqName) {
+				return true
+			}
+		}
+		return false
+	}
+
+	fd.Messages = filterMessages(fd.GetMessages(), fd.GetPackage(), excluded)
+
+	enums := make([]*protokit.EnumDescriptor, 0, len(fd.GetEnums()))
+	for _, enum := range fd.GetEnums() {
+		fqName := fqSymbolName(fd.GetPackage(), enum.GetName())
+		if !excluded(fqName, enum.GetOptions().GetDeprecated()) {
+			enums = append(enums, enum)
+		}
+	}
+	fd.Enums = enums
+
+	services := make([]*protokit.ServiceDescriptor, 0, len(fd.GetServices()))
+	for _, svc := range fd.GetServices() {
+		fqName := fqSymbolName(fd.GetPackage(), svc.GetName())
+		if excluded(fqName, svc.GetOptions().GetDeprecated()) {
+			continue
+		}
+
+		methods := make([]*protokit.MethodDescriptor, 0, len(svc.Methods))
+		for _, method := range svc.Methods {
+			methodFQName := fqName + "." + method.GetName()
+			if excluded(methodFQName, method.GetOptions().GetDeprecated()) {
+				continue
+			}
+			methods = append(methods, method)
+		}
+		svc.Methods = methods
+
+		services = append(services, svc)
+	}
+	fd.Services = services
+}
+
+// filterMessages applies excluded to messages and, recursively, to each message's nested messages, enums and
+// fields, returning the surviving messages. parentFQName is the fully-qualified name of the scope messages live
+// in (a package name at the top level, or an enclosing message's fully-qualified name when recursing).
+func filterMessages(messages []*protokit.Descriptor, parentFQName string, excluded func(string, bool) bool) []*protokit.Descriptor {
+	result := make([]*protokit.Descriptor, 0, len(messages))
+	for _, msg := range messages {
+		fqName := fqSymbolName(parentFQName, msg.GetName())
+		if excluded(fqName, msg.GetOptions().GetDeprecated()) {
+			continue
+		}
+
+		fields := make([]*descriptor.FieldDescriptorProto, 0, len(msg.GetField()))
+		for _, field := range msg.GetField() {
+			fieldFQName := fqName + "." + field.GetName()
+			if excluded(fieldFQName, field.GetOptions().GetDeprecated()) {
+				continue
+			}
+			fields = append(fields, field)
+		}
+		msg.Field = fields
+
+		msg.Messages = filterMessages(msg.GetMessages(), fqName, excluded)
+
+		nestedEnums := make([]*protokit.EnumDescriptor, 0, len(msg.GetEnums()))
+		for _, enum := range msg.GetEnums() {
+			enumFQName := fqSymbolName(fqName, enum.GetName())
+			if !excluded(enumFQName, enum.GetOptions().GetDeprecated()) {
+				nestedEnums = append(nestedEnums, enum)
+			}
+		}
+		msg.Enums = nestedEnums
+
+		result = append(result, msg)
+	}
+	return result
+}
+
+func fqSymbolName(pkg, name string) string {
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
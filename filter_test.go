@@ -0,0 +1,102 @@
+package gendoc
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/pseudomuto/protokit"
+)
+
+func mustCompile(t *testing.T, pattern string) *regexp.Regexp {
+	t.Helper()
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return re
+}
+
+func TestFilterFilesIncludeAndExclude(t *testing.T) {
+	fds := []*protokit.FileDescriptor{
+		{FileDescriptorProto: &descriptorpb.FileDescriptorProto{Name: proto.String("api/a.proto")}},
+		{FileDescriptorProto: &descriptorpb.FileDescriptorProto{Name: proto.String("api/b.proto")}},
+		{FileDescriptorProto: &descriptorpb.FileDescriptorProto{Name: proto.String("google/type.proto")}},
+	}
+
+	got := filterFiles(fds, []*regexp.Regexp{mustCompile(t, `^api/`)}, []*regexp.Regexp{mustCompile(t, `b\.proto$`)})
+
+	if len(got) != 1 || got[0].GetName() != "api/a.proto" {
+		t.Errorf("got %v, want just api/a.proto (google/type.proto is outside the include allowlist, and exclusion wins over include for b.proto)", names(got))
+	}
+}
+
+func names(fds []*protokit.FileDescriptor) []string {
+	out := make([]string, len(fds))
+	for i, fd := range fds {
+		out[i] = fd.GetName()
+	}
+	return out
+}
+
+// nestedFixture builds a FileDescriptor with a top-level message containing a nested message, which itself
+// contains a deprecated nested field and a nested enum, so filterSymbols' recursion can be exercised at every
+// level.
+func nestedFixture() *protokit.FileDescriptor {
+	innerField := &descriptorpb.FieldDescriptorProto{
+		Name:    proto.String("legacy_id"),
+		Options: &descriptorpb.FieldOptions{Deprecated: proto.Bool(true)},
+	}
+	innerEnum := &protokit.EnumDescriptor{
+		EnumDescriptorProto: &descriptorpb.EnumDescriptorProto{Name: proto.String("Kind")},
+	}
+	inner := &protokit.Descriptor{
+		DescriptorProto: &descriptorpb.DescriptorProto{
+			Name:  proto.String("Inner"),
+			Field: []*descriptorpb.FieldDescriptorProto{innerField},
+		},
+	}
+	inner.Enums = []*protokit.EnumDescriptor{innerEnum}
+
+	outer := &protokit.Descriptor{
+		DescriptorProto: &descriptorpb.DescriptorProto{Name: proto.String("Outer")},
+	}
+	outer.Messages = []*protokit.Descriptor{inner}
+
+	fd := &protokit.FileDescriptor{
+		FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+			Name:    proto.String("nested.proto"),
+			Package: proto.String("pkg"),
+		},
+	}
+	fd.Messages = []*protokit.Descriptor{outer}
+	return fd
+}
+
+func TestFilterSymbolsRecursesIntoNestedMessages(t *testing.T) {
+	fd := nestedFixture()
+	filterSymbols(fd, &Options{ExcludeDeprecated: true})
+
+	outer := fd.Messages[0]
+	if len(outer.GetMessages()) != 1 {
+		t.Fatalf("Outer.Messages = %d, want Inner to survive (it isn't itself deprecated)", len(outer.GetMessages()))
+	}
+
+	inner := outer.GetMessages()[0]
+	if len(inner.GetField()) != 0 {
+		t.Errorf("Inner.Field = %+v, want the deprecated legacy_id field dropped", inner.GetField())
+	}
+	if len(inner.GetEnums()) != 1 || inner.GetEnums()[0].GetName() != "Kind" {
+		t.Errorf("Inner.Enums = %+v, want Kind retained (it isn't deprecated)", inner.GetEnums())
+	}
+}
+
+func TestFilterSymbolsExcludesByName(t *testing.T) {
+	fd := nestedFixture()
+	filterSymbols(fd, &Options{ExcludeSymbols: []*regexp.Regexp{mustCompile(t, `^pkg\.Outer\.Inner$`)}})
+
+	if len(fd.Messages[0].GetMessages()) != 0 {
+		t.Errorf("Outer.Messages = %+v, want Inner excluded by its fully-qualified name", fd.Messages[0].GetMessages())
+	}
+}
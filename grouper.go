@@ -0,0 +1,249 @@
+package gendoc
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/pseudomuto/protokit"
+)
+
+// Group is a single unit of output: the FileDescriptors feeding it, plus enough context for outputName to expand
+// a user-supplied output file name template against it.
+type Group struct {
+	Dir       string
+	Service   string
+	Message   string
+	ProtoFile string
+	Files     []*protokit.FileDescriptor
+}
+
+// outputName expands pattern (a Go text/template string, e.g. "{{.Service}}.md") against g, joining the result
+// under g.Dir. A literal pattern with no template actions (the common "index.html" case) passes through
+// unchanged.
+func (g *Group) outputName(pattern string) (string, error) {
+	tmpl, err := template.New("out").Parse(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, g); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(g.Dir, buf.String()), nil
+}
+
+// Grouper partitions a parsed, already-filtered FileDescriptor set into the Groups that Plugin.Generate renders
+// into separate output files. ByDirectory is the plugin's historical behavior; ByService, ByMessage and
+// ByProtoFile split output more finely for large APIs where a single index file is unmanageable.
+type Grouper interface {
+	Group(fds []*protokit.FileDescriptor) []*Group
+}
+
+// ByDirectory groups FileDescriptors by their source directory (or "./" when sourceRelative is false), which
+// collapses to a single group when sourceRelative is false.
+type ByDirectory struct {
+	SourceRelative bool
+}
+
+// Group implements Grouper.
+func (g ByDirectory) Group(fds []*protokit.FileDescriptor) []*Group {
+	groups := make(map[string]*Group)
+	order := make([]string, 0)
+
+	for _, fd := range fds {
+		dir := ""
+		if g.SourceRelative {
+			dir, _ = filepath.Split(fd.GetName())
+		}
+		if dir == "" {
+			dir = "./"
+		}
+
+		if _, ok := groups[dir]; !ok {
+			groups[dir] = &Group{Dir: dir}
+			order = append(order, dir)
+		}
+		groups[dir].Files = append(groups[dir].Files, fd)
+	}
+
+	result := make([]*Group, len(order))
+	for i, dir := range order {
+		result[i] = groups[dir]
+	}
+	return result
+}
+
+// ByProtoFile puts each proto file into its own group, named after the proto file itself.
+type ByProtoFile struct{}
+
+// Group implements Grouper.
+func (ByProtoFile) Group(fds []*protokit.FileDescriptor) []*Group {
+	groups := make([]*Group, len(fds))
+	for i, fd := range fds {
+		groups[i] = &Group{ProtoFile: fd.GetName(), Files: []*protokit.FileDescriptor{fd}}
+	}
+	return groups
+}
+
+// ByService puts every service into its own group. FileDescriptors without services don't contribute a group.
+// Each group's FileDescriptor is scoped down to that service plus the messages and enums its methods reference
+// (see scopedToService): no other services, and none of the source file's unreferenced messages or enums, so the
+// rendered output documents that service alone rather than a renamed copy of the whole file.
+type ByService struct{}
+
+// Group implements Grouper.
+func (ByService) Group(fds []*protokit.FileDescriptor) []*Group {
+	groups := make([]*Group, 0)
+	for _, fd := range fds {
+		for _, svc := range fd.GetServices() {
+			groups = append(groups, &Group{
+				Service: svc.GetName(),
+				Files:   []*protokit.FileDescriptor{scopedToService(fd, svc)},
+			})
+		}
+	}
+	return groups
+}
+
+// ByMessage puts every top-level message into its own group. FileDescriptors without messages don't contribute
+// a group. Each group's FileDescriptor is scoped down to that message plus the top-level messages and enums its
+// fields reference (see scopedToMessage), for the same reason ByService scopes down to a single service.
+type ByMessage struct{}
+
+// Group implements Grouper.
+func (ByMessage) Group(fds []*protokit.FileDescriptor) []*Group {
+	groups := make([]*Group, 0)
+	for _, fd := range fds {
+		for _, msg := range fd.GetMessages() {
+			groups = append(groups, &Group{
+				Message: msg.GetName(),
+				Files:   []*protokit.FileDescriptor{scopedToMessage(fd, msg)},
+			})
+		}
+	}
+	return groups
+}
+
+// scopedToService returns a shallow copy of fd whose Services contains only svc, and whose Messages/Enums are
+// narrowed to the request/response messages (and the enums/messages those transitively reference) svc's methods
+// actually use, so a template rendering it produces a self-contained doc for that service: the RPCs plus the
+// field/type information a reader needs to understand their request and response shapes.
+func scopedToService(fd *protokit.FileDescriptor, svc *protokit.ServiceDescriptor) *protokit.FileDescriptor {
+	roots := make([]string, 0, len(svc.Methods)*2)
+	for _, method := range svc.Methods {
+		roots = append(roots, method.GetInputType(), method.GetOutputType())
+	}
+
+	scoped := *fd
+	scoped.Services = []*protokit.ServiceDescriptor{svc}
+	scoped.Messages, scoped.Enums = scopeReferencedTypes(fd, nil, roots)
+	return &scoped
+}
+
+// scopedToMessage returns a shallow copy of fd whose Messages contains msg plus any other top-level
+// message/enum its fields transitively reference, and whose Services are cleared, so a template rendering it
+// produces a self-contained doc for that message: nested field types (including ones typed with a top-level
+// enum) resolve instead of dangling.
+func scopedToMessage(fd *protokit.FileDescriptor, msg *protokit.Descriptor) *protokit.FileDescriptor {
+	scoped := *fd
+	scoped.Services = nil
+	scoped.Messages, scoped.Enums = scopeReferencedTypes(fd, []string{fqSymbolName(fd.GetPackage(), msg.GetName())}, nil)
+	return &scoped
+}
+
+// scopeReferencedTypes resolves which of fd's top-level messages and enums need to stay in scope: every message
+// named in forceMessages, plus every top-level message/enum transitively reachable from forceMessages' and
+// typeRoots' field types. Both forceMessages and typeRoots hold fully-qualified names, with or without the leading
+// '.' protokit stores on a field (method.GetInputType()/GetOutputType() or field.GetTypeName(), e.g.
+// ".mypackage.MyMessage"; fqSymbolName produces the undotted "mypackage.MyMessage"). Types outside fd's own package (imported from another file) can't be resolved here and are
+// skipped, matching the scope of a single FileDescriptor.
+func scopeReferencedTypes(fd *protokit.FileDescriptor, forceMessages, typeRoots []string) ([]*protokit.Descriptor, []*protokit.EnumDescriptor) {
+	messagesByName := make(map[string]*protokit.Descriptor, len(fd.GetMessages()))
+	for _, msg := range fd.GetMessages() {
+		messagesByName[msg.GetName()] = msg
+	}
+	enumsByName := make(map[string]*protokit.EnumDescriptor, len(fd.GetEnums()))
+	for _, enum := range fd.GetEnums() {
+		enumsByName[enum.GetName()] = enum
+	}
+
+	includedMessages := make(map[string]bool)
+	includedEnums := make(map[string]bool)
+
+	queue := make([]string, 0, len(forceMessages)+len(typeRoots))
+	queue = append(queue, forceMessages...)
+	queue = append(queue, typeRoots...)
+
+	for len(queue) > 0 {
+		name := topLevelTypeName(queue[0], fd.GetPackage())
+		queue = queue[1:]
+
+		if name == "" || includedMessages[name] || includedEnums[name] {
+			continue
+		}
+
+		if msg, ok := messagesByName[name]; ok {
+			includedMessages[name] = true
+			queue = append(queue, fieldTypeNames(msg)...)
+			continue
+		}
+		if _, ok := enumsByName[name]; ok {
+			includedEnums[name] = true
+		}
+	}
+
+	messages := make([]*protokit.Descriptor, 0, len(includedMessages))
+	for _, msg := range fd.GetMessages() {
+		if includedMessages[msg.GetName()] {
+			messages = append(messages, msg)
+		}
+	}
+
+	enums := make([]*protokit.EnumDescriptor, 0, len(includedEnums))
+	for _, enum := range fd.GetEnums() {
+		if includedEnums[enum.GetName()] {
+			enums = append(enums, enum)
+		}
+	}
+
+	return messages, enums
+}
+
+// topLevelTypeName reduces a field's fully-qualified type name (e.g. ".mypackage.Outer.Inner") to the name of
+// the top-level message or enum that owns it (e.g. "Outer"), or "" if typeName isn't in pkg (an imported type
+// scopeReferencedTypes can't resolve from fd alone).
+func topLevelTypeName(typeName, pkg string) string {
+	name := strings.TrimPrefix(typeName, ".")
+
+	if pkg != "" {
+		prefix := pkg + "."
+		if !strings.HasPrefix(name, prefix) {
+			return ""
+		}
+		name = strings.TrimPrefix(name, prefix)
+	}
+
+	if idx := strings.Index(name, "."); idx >= 0 {
+		name = name[:idx]
+	}
+	return name
+}
+
+// fieldTypeNames collects every message/enum type name referenced by msg's fields, recursing into nested
+// messages so a reference buried inside a nested type is still found.
+func fieldTypeNames(msg *protokit.Descriptor) []string {
+	names := make([]string, 0, len(msg.GetField()))
+	for _, field := range msg.GetField() {
+		if field.GetTypeName() != "" {
+			names = append(names, field.GetTypeName())
+		}
+	}
+	for _, nested := range msg.GetMessages() {
+		names = append(names, fieldTypeNames(nested)...)
+	}
+	return names
+}
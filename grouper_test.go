@@ -0,0 +1,140 @@
+package gendoc
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/pseudomuto/protokit"
+)
+
+// libraryFixture builds a FileDescriptor for a small library-style API: a Shelf message with a repeated Book
+// field typed with the top-level Book message, a Book.Status enum field, a Genre top-level enum nobody
+// references, and a Library service whose RPCs use Shelf/Book as request/response types.
+func libraryFixture() *protokit.FileDescriptor {
+	statusEnum := &protokit.EnumDescriptor{
+		EnumDescriptorProto: &descriptorpb.EnumDescriptorProto{Name: proto.String("Status")},
+	}
+	genreEnum := &protokit.EnumDescriptor{
+		EnumDescriptorProto: &descriptorpb.EnumDescriptorProto{Name: proto.String("Genre")},
+	}
+
+	book := &protokit.Descriptor{
+		DescriptorProto: &descriptorpb.DescriptorProto{
+			Name: proto.String("Book"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("status"), TypeName: proto.String(".library.Status")},
+			},
+		},
+	}
+	shelf := &protokit.Descriptor{
+		DescriptorProto: &descriptorpb.DescriptorProto{
+			Name: proto.String("Shelf"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("books"), TypeName: proto.String(".library.Book")},
+			},
+		},
+	}
+	getShelfRequest := &protokit.Descriptor{
+		DescriptorProto: &descriptorpb.DescriptorProto{Name: proto.String("GetShelfRequest")},
+	}
+
+	svc := &protokit.ServiceDescriptor{
+		ServiceDescriptorProto: &descriptorpb.ServiceDescriptorProto{Name: proto.String("Library")},
+	}
+	svc.Methods = []*protokit.MethodDescriptor{
+		{MethodDescriptorProto: &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String("GetShelf"),
+			InputType:  proto.String(".library.GetShelfRequest"),
+			OutputType: proto.String(".library.Shelf"),
+		}},
+	}
+
+	fd := &protokit.FileDescriptor{
+		FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+			Name:    proto.String("library.proto"),
+			Package: proto.String("library"),
+		},
+	}
+	fd.Messages = []*protokit.Descriptor{getShelfRequest, shelf, book}
+	fd.Enums = []*protokit.EnumDescriptor{statusEnum, genreEnum}
+	fd.Services = []*protokit.ServiceDescriptor{svc}
+
+	return fd
+}
+
+func TestScopedToServiceRetainsReferencedMessagesAndEnums(t *testing.T) {
+	fd := libraryFixture()
+	scoped := scopedToService(fd, fd.Services[0])
+
+	if len(scoped.Services) != 1 || scoped.Services[0].GetName() != "Library" {
+		t.Fatalf("Services = %+v, want just Library", scoped.Services)
+	}
+
+	gotMessages := messageNames(scoped.Messages)
+	wantMessages := map[string]bool{"GetShelfRequest": true, "Shelf": true, "Book": true}
+	if len(gotMessages) != len(wantMessages) || !gotMessages["GetShelfRequest"] || !gotMessages["Shelf"] || !gotMessages["Book"] {
+		t.Errorf("Messages = %v, want %v (request/response plus transitively referenced Book)", gotMessages, wantMessages)
+	}
+
+	gotEnums := enumNames(scoped.Enums)
+	if !gotEnums["Status"] {
+		t.Errorf("Enums = %v, want Status retained (referenced by Book.status)", gotEnums)
+	}
+	if gotEnums["Genre"] {
+		t.Errorf("Enums = %v, want unreferenced Genre dropped", gotEnums)
+	}
+}
+
+func TestScopedToMessageRetainsReferencedEnumsAndClearsServices(t *testing.T) {
+	fd := libraryFixture()
+	book := fd.GetMessage("Book")
+	scoped := scopedToMessage(fd, book)
+
+	if len(scoped.Services) != 0 {
+		t.Errorf("Services = %+v, want none", scoped.Services)
+	}
+
+	gotMessages := messageNames(scoped.Messages)
+	if len(gotMessages) != 1 || !gotMessages["Book"] {
+		t.Errorf("Messages = %v, want just Book", gotMessages)
+	}
+
+	gotEnums := enumNames(scoped.Enums)
+	if !gotEnums["Status"] {
+		t.Errorf("Enums = %v, want Status retained (referenced by Book.status)", gotEnums)
+	}
+	if gotEnums["Genre"] {
+		t.Errorf("Enums = %v, want unreferenced Genre dropped", gotEnums)
+	}
+}
+
+func TestByProtoFileGroupsOneGroupPerFile(t *testing.T) {
+	a := &protokit.FileDescriptor{FileDescriptorProto: &descriptorpb.FileDescriptorProto{Name: proto.String("a.proto")}}
+	b := &protokit.FileDescriptor{FileDescriptorProto: &descriptorpb.FileDescriptorProto{Name: proto.String("b.proto")}}
+
+	groups := ByProtoFile{}.Group([]*protokit.FileDescriptor{a, b})
+
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+	if groups[0].ProtoFile != "a.proto" || groups[1].ProtoFile != "b.proto" {
+		t.Errorf("groups = %+v, want ProtoFile set to each source file's name", groups)
+	}
+}
+
+func messageNames(messages []*protokit.Descriptor) map[string]bool {
+	names := make(map[string]bool, len(messages))
+	for _, m := range messages {
+		names[m.GetName()] = true
+	}
+	return names
+}
+
+func enumNames(enums []*protokit.EnumDescriptor) map[string]bool {
+	names := make(map[string]bool, len(enums))
+	for _, e := range enums {
+		names[e.GetName()] = true
+	}
+	return names
+}
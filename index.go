@@ -0,0 +1,165 @@
+package gendoc
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pseudomuto/protokit"
+)
+
+// SymbolIndex maps every fully-qualified proto symbol (message, enum, field, service, method) to a canonical,
+// deterministic anchor and to the output file it lands in once Grouper has split the descriptor set across
+// files. It's built once per Generate call, before rendering, so cross-references keep working even when
+// per_service/per_message grouping spreads output across many files: RewriteCrossFileLinks uses it to add the
+// right file prefix to a rendered template's in-document anchor links once that link's target has moved to a
+// different output file.
+type SymbolIndex struct {
+	entries map[string]IndexEntry
+
+	// byLegacyAnchor maps a symbol's LegacyAnchor slug back to its fully-qualified name, so
+	// RewriteCrossFileLinks can resolve an `href="#slug"` it finds in rendered output back to an IndexEntry
+	// without needing the template layer to know about fully-qualified names at all.
+	//
+	// The HTML/Markdown templates themselves live outside this source tree, and protoc-gen-doc's own history
+	// has used more than one in-document anchor convention for a symbol (a dash-joined slug in some template
+	// versions, the bare fully-qualified name in others). RewriteCrossFileLinks can't assume which one the
+	// configured template emits, so this index keys by both: byLegacyAnchor for the dash-joined slug, and
+	// entries itself (by fully-qualified name) for the literal-name form.
+	byLegacyAnchor map[string]string
+}
+
+// IndexEntry is where a single fully-qualified symbol landed: which output file, and under which anchor.
+type IndexEntry struct {
+	Anchor string
+	File   string
+}
+
+// NewSymbolIndex returns an empty SymbolIndex.
+func NewSymbolIndex() *SymbolIndex {
+	return &SymbolIndex{
+		entries:        make(map[string]IndexEntry),
+		byLegacyAnchor: make(map[string]string),
+	}
+}
+
+// Anchor computes the canonical, stable anchor for a fully-qualified symbol name: the first 8 hex characters of
+// its SHA-1 digest. It's deterministic across runs (unlike e.g. a slug, it can't collide between symbols that
+// differ only in characters a slugifier strips) so external docs can deep-link to it permanently.
+func Anchor(fqName string) string {
+	sum := sha1.Sum([]byte(fqName))
+	return hex.EncodeToString(sum[:])[:8]
+}
+
+// LegacyAnchor recreates protoc-gen-doc's historical slug-based anchor (fqName with '.' replaced by '-'). It
+// populates RedirectsFile's old-anchor side when migrating a docset from a previous grouping mode, and Add uses it
+// to key SymbolIndex.byLegacyAnchor so RewriteCrossFileLinks can resolve slug-style links.
+func LegacyAnchor(fqName string) string {
+	return strings.ReplaceAll(fqName, ".", "-")
+}
+
+// Add records fqName's location and returns the IndexEntry it was assigned.
+func (idx *SymbolIndex) Add(fqName, file string) IndexEntry {
+	entry := IndexEntry{Anchor: Anchor(fqName), File: file}
+	idx.entries[fqName] = entry
+	idx.byLegacyAnchor[LegacyAnchor(fqName)] = fqName
+	return entry
+}
+
+// Lookup returns fqName's IndexEntry, if it was added to the index.
+func (idx *SymbolIndex) Lookup(fqName string) (IndexEntry, bool) {
+	entry, ok := idx.entries[fqName]
+	return entry, ok
+}
+
+// Redirects returns a JSON-marshalable map from each symbol's legacy `file#slug` anchor to its current
+// `file#hash` one, suitable for writing out as RedirectsFile.
+func (idx *SymbolIndex) Redirects() map[string]string {
+	redirects := make(map[string]string, len(idx.entries))
+	for fqName, entry := range idx.entries {
+		oldRef := fmt.Sprintf("%s#%s", entry.File, LegacyAnchor(fqName))
+		newRef := fmt.Sprintf("%s#%s", entry.File, entry.Anchor)
+		redirects[oldRef] = newRef
+	}
+	return redirects
+}
+
+// crossFileLinkPattern matches the in-document anchor links (`href="#slug"`) that protoc-gen-doc's HTML/Markdown
+// templates emit for type references; these are only valid when every symbol lands in the same output file.
+var crossFileLinkPattern = regexp.MustCompile(`href="#([^"]+)"`)
+
+// RewriteCrossFileLinks scans a rendered group's output for `href="#slug"` anchor links and, for any slug that
+// idx can resolve to a symbol landing in a different output file than currentFile, rewrites it to
+// `href="otherFile#slug"`. This is what keeps type references working as links once per_service/per_message
+// grouping splits a single proto file's symbols across multiple output files: idx must already contain every
+// group (built via BuildSymbolIndex before any group is rendered), since a symbol can be referenced from a file
+// that renders before the file it lives in.
+//
+// A slug resolves via resolveSlug, which tries both known anchor conventions (see SymbolIndex.byLegacyAnchor);
+// a slug matching neither is left untouched rather than guessed at.
+func RewriteCrossFileLinks(content []byte, idx *SymbolIndex, currentFile string) []byte {
+	if idx == nil || len(idx.entries) == 0 {
+		return content
+	}
+
+	return crossFileLinkPattern.ReplaceAllFunc(content, func(match []byte) []byte {
+		slug := string(crossFileLinkPattern.FindSubmatch(match)[1])
+
+		entry, ok := idx.resolveSlug(slug)
+		if !ok || entry.File == "" || entry.File == currentFile {
+			return match
+		}
+
+		return []byte(fmt.Sprintf(`href="%s#%s"`, entry.File, slug))
+	})
+}
+
+// resolveSlug resolves an in-document anchor slug found in rendered output back to the IndexEntry it refers to,
+// trying slug as a literal fully-qualified symbol name first, then as a LegacyAnchor dash-joined slug.
+func (idx *SymbolIndex) resolveSlug(slug string) (IndexEntry, bool) {
+	if entry, ok := idx.entries[slug]; ok {
+		return entry, true
+	}
+
+	if fqName, ok := idx.byLegacyAnchor[slug]; ok {
+		return idx.entries[fqName], true
+	}
+
+	return IndexEntry{}, false
+}
+
+// BuildSymbolIndex walks every FileDescriptor in group, indexing its messages, enums, services, methods and
+// fields under outputFile (the name the group was rendered to).
+func BuildSymbolIndex(idx *SymbolIndex, group *Group, outputFile string) {
+	for _, fd := range group.Files {
+		for _, msg := range fd.GetMessages() {
+			indexMessage(idx, fd.GetPackage(), msg, outputFile)
+		}
+		for _, enum := range fd.GetEnums() {
+			idx.Add(fqSymbolName(fd.GetPackage(), enum.GetName()), outputFile)
+		}
+		for _, svc := range fd.GetServices() {
+			svcFQName := fqSymbolName(fd.GetPackage(), svc.GetName())
+			idx.Add(svcFQName, outputFile)
+			for _, method := range svc.Methods {
+				idx.Add(svcFQName+"."+method.GetName(), outputFile)
+			}
+		}
+	}
+}
+
+func indexMessage(idx *SymbolIndex, pkg string, msg *protokit.Descriptor, outputFile string) {
+	fqName := fqSymbolName(pkg, msg.GetName())
+	idx.Add(fqName, outputFile)
+	for _, field := range msg.GetField() {
+		idx.Add(fqName+"."+field.GetName(), outputFile)
+	}
+	for _, nested := range msg.GetMessages() {
+		indexMessage(idx, fqName, nested, outputFile)
+	}
+	for _, enum := range msg.GetEnums() {
+		idx.Add(fqSymbolName(fqName, enum.GetName()), outputFile)
+	}
+}
@@ -0,0 +1,98 @@
+package gendoc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/pseudomuto/protokit"
+)
+
+func twoFileFixture() (shelf, book *protokit.FileDescriptor) {
+	shelfMsg := &protokit.Descriptor{
+		DescriptorProto: &descriptorpb.DescriptorProto{
+			Name: proto.String("Shelf"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("book"), TypeName: proto.String(".library.Book")},
+			},
+		},
+	}
+	shelf = &protokit.FileDescriptor{
+		FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+			Name:    proto.String("shelf.proto"),
+			Package: proto.String("library"),
+		},
+	}
+	shelf.Messages = []*protokit.Descriptor{shelfMsg}
+
+	bookMsg := &protokit.Descriptor{
+		DescriptorProto: &descriptorpb.DescriptorProto{Name: proto.String("Book")},
+	}
+	book = &protokit.FileDescriptor{
+		FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+			Name:    proto.String("book.proto"),
+			Package: proto.String("library"),
+		},
+	}
+	book.Messages = []*protokit.Descriptor{bookMsg}
+
+	return shelf, book
+}
+
+func TestRewriteCrossFileLinksResolvesBothAnchorConventions(t *testing.T) {
+	shelf, book := twoFileFixture()
+
+	idx := NewSymbolIndex()
+	BuildSymbolIndex(idx, &Group{Files: []*protokit.FileDescriptor{shelf}}, "shelf.html")
+	BuildSymbolIndex(idx, &Group{Files: []*protokit.FileDescriptor{book}}, "book.html")
+
+	literal := []byte(`<a href="#library.Book">Book</a>`)
+	got := RewriteCrossFileLinks(literal, idx, "shelf.html")
+	if !strings.Contains(string(got), `href="book.html#library.Book"`) {
+		t.Errorf("literal-name anchor: got %s, want href rewritten to book.html#library.Book", got)
+	}
+
+	legacy := []byte(`<a href="#library-Book">Book</a>`)
+	got = RewriteCrossFileLinks(legacy, idx, "shelf.html")
+	if !strings.Contains(string(got), `href="book.html#library-Book"`) {
+		t.Errorf("legacy-slug anchor: got %s, want href rewritten to book.html#library-Book", got)
+	}
+}
+
+func TestRewriteCrossFileLinksLeavesSameFileLinksAlone(t *testing.T) {
+	shelf, book := twoFileFixture()
+
+	idx := NewSymbolIndex()
+	BuildSymbolIndex(idx, &Group{Files: []*protokit.FileDescriptor{shelf}}, "shelf.html")
+	BuildSymbolIndex(idx, &Group{Files: []*protokit.FileDescriptor{book}}, "book.html")
+
+	content := []byte(`<a href="#library.Shelf">Shelf</a>`)
+	got := RewriteCrossFileLinks(content, idx, "shelf.html")
+	if string(got) != string(content) {
+		t.Errorf("got %s, want unchanged since library.Shelf already lives in shelf.html", got)
+	}
+}
+
+func TestRewriteCrossFileLinksLeavesUnknownSlugsAlone(t *testing.T) {
+	idx := NewSymbolIndex()
+	idx.Add("library.Shelf", "shelf.html")
+
+	content := []byte(`<a href="#not-a-known-symbol">?</a>`)
+	got := RewriteCrossFileLinks(content, idx, "shelf.html")
+	if string(got) != string(content) {
+		t.Errorf("got %s, want unchanged for a slug the index can't resolve", got)
+	}
+}
+
+func TestSymbolIndexRedirectsMapLegacyToCurrentAnchor(t *testing.T) {
+	idx := NewSymbolIndex()
+	entry := idx.Add("library.Shelf", "shelf.html")
+
+	redirects := idx.Redirects()
+	want := "shelf.html#" + entry.Anchor
+	got, ok := redirects["shelf.html#library-Shelf"]
+	if !ok || got != want {
+		t.Errorf("redirects[shelf.html#library-Shelf] = %q, %v, want %q, true", got, ok, want)
+	}
+}
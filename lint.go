@@ -0,0 +1,152 @@
+package gendoc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/pseudomuto/protokit"
+	"gopkg.in/yaml.v2"
+)
+
+// RenderTypeLint is a RenderType that doesn't render a template at all: selecting it causes Plugin.Generate to
+// walk the parsed descriptor set and enforce LintRules instead, reporting violations as protoc-friendly
+// `file:line: message` diagnostics via the response's Error field.
+const RenderTypeLint RenderType = 100
+
+// LintRules describes the set of configurable rules enforced by RenderTypeLint. Any zero-valued field is treated as
+// "not enforced".
+//
+// RequireStreamingDeadline and PreventFieldRenumbering are recognized (so rules files can reference them in a
+// readable diagnostic) but rejected by LoadLintRules: both need information a single plugin invocation doesn't
+// have (a registered deadline extension, and a prior schema snapshot to diff against, respectively).
+type LintRules struct {
+	RequireLeadingComments   bool   `yaml:"require_leading_comments" json:"require_leading_comments"`
+	UniqueRequestResponse    bool   `yaml:"unique_request_response" json:"unique_request_response"`
+	RequireStreamingDeadline bool   `yaml:"require_streaming_deadline" json:"require_streaming_deadline"`
+	PackageNamePattern       string `yaml:"package_name_pattern" json:"package_name_pattern"`
+	PreventFieldRenumbering  bool   `yaml:"prevent_field_renumbering" json:"prevent_field_renumbering"`
+
+	packageNameRegexp *regexp.Regexp
+}
+
+// LintViolation is a single rule violation found while walking the parsed FileDescriptor set. It renders as a
+// protoc-friendly `file:line: message` diagnostic.
+type LintViolation struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (v LintViolation) String() string {
+	return fmt.Sprintf("%s:%d: %s", v.File, v.Line, v.Message)
+}
+
+// LoadLintRules reads and parses a YAML or JSON rules file (YAML is a superset of JSON, so a single unmarshaler
+// handles both).
+func LoadLintRules(path string) (*LintRules, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := new(LintRules)
+	if err := yaml.Unmarshal(data, rules); err != nil {
+		return nil, fmt.Errorf("failed to parse lint rules %s: %v", path, err)
+	}
+
+	if rules.PackageNamePattern != "" {
+		re, err := regexp.Compile(rules.PackageNamePattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid package_name_pattern %q: %v", rules.PackageNamePattern, err)
+		}
+		rules.packageNameRegexp = re
+	}
+
+	// Both of these require information this plugin invocation doesn't have: prevent_field_renumbering needs a
+	// prior schema snapshot to diff against (protoc already rejects duplicate field numbers within a single
+	// compile, so checking the current snapshot alone can never catch a renumbering), and
+	// require_streaming_deadline needs a project-specific MethodOptions extension that isn't registered here.
+	// Reject them at load time rather than silently enforcing a rule that can never fire, or always fires.
+	if rules.PreventFieldRenumbering {
+		return nil, fmt.Errorf("lint rules %s: prevent_field_renumbering is not implemented (it needs a prior schema snapshot to diff against, which this plugin doesn't have)", path)
+	}
+	if rules.RequireStreamingDeadline {
+		return nil, fmt.Errorf("lint rules %s: require_streaming_deadline is not implemented (it needs a registered deadline extension on MethodOptions)", path)
+	}
+
+	return rules, nil
+}
+
+// RunLint walks the parsed FileDescriptor set, enforcing rules, and returns every violation found. A nil or empty
+// result means the set is clean.
+func RunLint(fds []*protokit.FileDescriptor, rules *LintRules) []LintViolation {
+	violations := make([]LintViolation, 0)
+
+	for _, fd := range fds {
+		if rules.packageNameRegexp != nil && !rules.packageNameRegexp.MatchString(fd.GetPackage()) {
+			violations = append(violations, LintViolation{
+				File:    fd.GetName(),
+				Line:    1,
+				Message: fmt.Sprintf("package %q does not match pattern %q", fd.GetPackage(), rules.PackageNamePattern),
+			})
+		}
+
+		seenRequestResponse := make(map[string]string)
+
+		for _, svc := range fd.GetServices() {
+			for _, method := range svc.Methods {
+				line := 1
+
+				if rules.RequireLeadingComments && method.GetComments().GetLeading() == "" {
+					violations = append(violations, LintViolation{
+						File:    fd.GetName(),
+						Line:    line,
+						Message: fmt.Sprintf("rpc %s.%s is missing a leading comment", svc.GetName(), method.GetName()),
+					})
+				}
+
+				if rules.UniqueRequestResponse {
+					for _, t := range []string{method.GetInputType(), method.GetOutputType()} {
+						if owner, ok := seenRequestResponse[t]; ok && owner != method.GetName() {
+							violations = append(violations, LintViolation{
+								File:    fd.GetName(),
+								Line:    line,
+								Message: fmt.Sprintf("message %s is reused by more than one RPC (%s and %s)", t, owner, method.GetName()),
+							})
+						}
+						seenRequestResponse[t] = method.GetName()
+					}
+				}
+			}
+		}
+	}
+
+	return violations
+}
+
+// lintResponse runs the lint rules against fds and turns the result into a CodeGeneratorResponse. Any violation is
+// reported as a protoc-friendly `file:line: message` diagnostic via the response's Error field, which protoc
+// surfaces to the caller and which causes protoc itself to exit non-zero.
+func lintResponse(fds []*protokit.FileDescriptor, rules *LintRules) (*plugin_go.CodeGeneratorResponse, error) {
+	if rules == nil {
+		return nil, fmt.Errorf("lint: no rules file configured (use --doc_opt=type=lint,rules=<path>)")
+	}
+
+	violations := RunLint(fds, rules)
+	if len(violations) == 0 {
+		return new(plugin_go.CodeGeneratorResponse), nil
+	}
+
+	lines := make([]string, len(violations))
+	for i, v := range violations {
+		lines[i] = v.String()
+	}
+
+	resp := new(plugin_go.CodeGeneratorResponse)
+	resp.Error = proto.String(strings.Join(lines, "\n"))
+	return resp, nil
+}
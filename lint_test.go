@@ -0,0 +1,71 @@
+package gendoc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/pseudomuto/protokit"
+)
+
+func writeLintRulesFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadLintRulesRejectsUnimplementableRules(t *testing.T) {
+	cases := []string{
+		"prevent_field_renumbering: true\n",
+		"require_streaming_deadline: true\n",
+	}
+
+	for _, rules := range cases {
+		path := writeLintRulesFile(t, rules)
+		if _, err := LoadLintRules(path); err == nil {
+			t.Errorf("LoadLintRules(%q) = nil error, want one rejecting the unimplementable rule", rules)
+		}
+	}
+}
+
+func TestLoadLintRulesCompilesPackageNamePattern(t *testing.T) {
+	path := writeLintRulesFile(t, "package_name_pattern: \"^myapp\\\\.\"\n")
+
+	rules, err := LoadLintRules(path)
+	if err != nil {
+		t.Fatalf("LoadLintRules: %v", err)
+	}
+	if rules.packageNameRegexp == nil {
+		t.Fatal("expected packageNameRegexp to be compiled from package_name_pattern")
+	}
+}
+
+func TestRunLintRequireLeadingComments(t *testing.T) {
+	fd := &protokit.FileDescriptor{
+		FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+			Name:    proto.String("todo.proto"),
+			Package: proto.String("todo"),
+		},
+	}
+	svc := &protokit.ServiceDescriptor{
+		ServiceDescriptorProto: &descriptorpb.ServiceDescriptorProto{Name: proto.String("Todo")},
+	}
+	svc.Methods = []*protokit.MethodDescriptor{
+		{
+			MethodDescriptorProto: &descriptorpb.MethodDescriptorProto{Name: proto.String("List")},
+			Comments:              &protokit.Comment{},
+		},
+	}
+	fd.Services = []*protokit.ServiceDescriptor{svc}
+
+	violations := RunLint([]*protokit.FileDescriptor{fd}, &LintRules{RequireLeadingComments: true})
+
+	if len(violations) != 1 {
+		t.Fatalf("got %d violations, want 1: %+v", len(violations), violations)
+	}
+}
@@ -0,0 +1,325 @@
+package gendoc
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/pseudomuto/protokit"
+	annotations "google.golang.org/genproto/googleapis/api/annotations"
+	"gopkg.in/yaml.v2"
+)
+
+// RenderTypeOpenAPI and RenderTypeAsyncAPI render the parsed descriptor set as a machine-readable API contract
+// instead of human-oriented documentation. Like RenderTypeLint, they bypass the template/NewTemplate pipeline
+// used by the human-facing renderers (HTML/Markdown/JSON/DocBook): the "template" here is the document schema
+// itself, expanded through a default or user-supplied text/template.
+const (
+	RenderTypeOpenAPI  RenderType = 101
+	RenderTypeAsyncAPI RenderType = 102
+)
+
+// OpenAPIDocument is a (subset of an) OpenAPI 3.1 document, built from the parsed FileDescriptor set.
+type OpenAPIDocument struct {
+	OpenAPI    string                     `yaml:"openapi" json:"openapi"`
+	Info       OpenAPIInfo                `yaml:"info" json:"info"`
+	Paths      map[string]OpenAPIPathItem `yaml:"paths" json:"paths"`
+	Components OpenAPIComponents          `yaml:"components" json:"components"`
+}
+
+// OpenAPIInfo is the document's `info` object.
+type OpenAPIInfo struct {
+	Title   string `yaml:"title" json:"title"`
+	Version string `yaml:"version" json:"version"`
+}
+
+// OpenAPIPathItem is a single entry under `paths`, keyed by HTTP method.
+type OpenAPIPathItem struct {
+	Get    *OpenAPIOperation `yaml:"get,omitempty" json:"get,omitempty"`
+	Post   *OpenAPIOperation `yaml:"post,omitempty" json:"post,omitempty"`
+	Put    *OpenAPIOperation `yaml:"put,omitempty" json:"put,omitempty"`
+	Delete *OpenAPIOperation `yaml:"delete,omitempty" json:"delete,omitempty"`
+	Patch  *OpenAPIOperation `yaml:"patch,omitempty" json:"patch,omitempty"`
+}
+
+// OpenAPIOperation describes one RPC as an OpenAPI operation.
+type OpenAPIOperation struct {
+	Summary     string                     `yaml:"summary,omitempty" json:"summary,omitempty"`
+	Description string                     `yaml:"description,omitempty" json:"description,omitempty"`
+	OperationID string                     `yaml:"operationId" json:"operationId"`
+	Parameters  []OpenAPIParameter         `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody        `yaml:"requestBody,omitempty" json:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `yaml:"responses" json:"responses"`
+}
+
+// OpenAPIParameter describes a single path parameter extracted from a templated google.api.http path, e.g. the
+// `name` in `/v1/{name=shelves/*}`.
+type OpenAPIParameter struct {
+	Name     string        `yaml:"name" json:"name"`
+	In       string        `yaml:"in" json:"in"`
+	Required bool          `yaml:"required" json:"required"`
+	Schema   OpenAPISchema `yaml:"schema" json:"schema"`
+}
+
+// OpenAPIRequestBody wraps a schema $ref for an operation's request message.
+type OpenAPIRequestBody struct {
+	Content map[string]OpenAPIMediaType `yaml:"content" json:"content"`
+}
+
+// OpenAPIResponse wraps a schema $ref for an operation's response message.
+type OpenAPIResponse struct {
+	Description string                      `yaml:"description" json:"description"`
+	Content     map[string]OpenAPIMediaType `yaml:"content,omitempty" json:"content,omitempty"`
+}
+
+// OpenAPIMediaType names the schema backing a request/response body; protoc-gen-doc only emits `application/json`.
+type OpenAPIMediaType struct {
+	Schema OpenAPISchemaRef `yaml:"schema" json:"schema"`
+}
+
+// OpenAPISchemaRef is a `$ref` pointer into components.schemas.
+type OpenAPISchemaRef struct {
+	Ref string `yaml:"$ref" json:"$ref"`
+}
+
+// OpenAPIComponents holds the `components.schemas` produced from proto messages and enums.
+type OpenAPIComponents struct {
+	Schemas map[string]OpenAPISchema `yaml:"schemas" json:"schemas"`
+}
+
+// OpenAPISchema is a (subset of an) OpenAPI schema object, covering the shapes protoc-gen-doc can derive from a
+// message or enum descriptor without a full proto3-to-JSON-Schema mapping.
+type OpenAPISchema struct {
+	Type        string                   `yaml:"type,omitempty" json:"type,omitempty"`
+	Description string                   `yaml:"description,omitempty" json:"description,omitempty"`
+	Properties  map[string]OpenAPISchema `yaml:"properties,omitempty" json:"properties,omitempty"`
+	Items       *OpenAPISchema           `yaml:"items,omitempty" json:"items,omitempty"`
+	Enum        []string                 `yaml:"enum,omitempty" json:"enum,omitempty"`
+	Ref         string                   `yaml:"$ref,omitempty" json:"$ref,omitempty"`
+}
+
+// BuildOpenAPIDocument translates fds into an OpenAPIDocument: services become paths (using google.api.http
+// annotations when present, falling back to the gRPC-transcoding-free `/{package}.{Service}/{Method}` POST
+// convention), and messages/enums become component schemas.
+func BuildOpenAPIDocument(fds []*protokit.FileDescriptor) *OpenAPIDocument {
+	doc := &OpenAPIDocument{
+		OpenAPI: "3.1.0",
+		Info:    OpenAPIInfo{Title: "API", Version: "0.0.0"},
+		Paths:   make(map[string]OpenAPIPathItem),
+		Components: OpenAPIComponents{
+			Schemas: make(map[string]OpenAPISchema),
+		},
+	}
+
+	for _, fd := range fds {
+		for _, msg := range fd.GetMessages() {
+			addOpenAPIMessageSchema(doc, fd, msg)
+		}
+		for _, enum := range fd.GetEnums() {
+			addOpenAPIEnumSchema(doc, fd, enum)
+		}
+
+		for _, svc := range fd.GetServices() {
+			for _, method := range svc.Methods {
+				if method.GetClientStreaming() || method.GetServerStreaming() {
+					continue
+				}
+
+				rawPath, httpMethod := openAPIHTTPPath(fd, svc, method)
+				path, params := openAPIPathParameters(rawPath)
+				op := &OpenAPIOperation{
+					Summary:     strings.TrimSpace(method.GetComments().GetLeading()),
+					OperationID: fmt.Sprintf("%s_%s", svc.GetName(), method.GetName()),
+					Parameters:  params,
+					Responses: map[string]OpenAPIResponse{
+						"200": {
+							Description: "OK",
+							Content: map[string]OpenAPIMediaType{
+								"application/json": {Schema: openAPISchemaRefFor(method.GetOutputType())},
+							},
+						},
+					},
+				}
+
+				// GET/DELETE requests carry their input via the path/query, not a body; most OpenAPI tooling
+				// treats a requestBody on either as meaningless or rejects it outright.
+				if httpMethod != "GET" && httpMethod != "DELETE" {
+					op.RequestBody = &OpenAPIRequestBody{
+						Content: map[string]OpenAPIMediaType{
+							"application/json": {Schema: openAPISchemaRefFor(method.GetInputType())},
+						},
+					}
+				}
+
+				item := doc.Paths[path]
+				switch httpMethod {
+				case "GET":
+					item.Get = op
+				case "PUT":
+					item.Put = op
+				case "DELETE":
+					item.Delete = op
+				case "PATCH":
+					item.Patch = op
+				default:
+					item.Post = op
+				}
+				doc.Paths[path] = item
+			}
+		}
+	}
+
+	return doc
+}
+
+// openAPIHTTPPath resolves a method's HTTP path and verb from a google.api.http annotation when present, and
+// falls back to the `/{package}.{Service}/{Method}` convention (always POST) otherwise.
+func openAPIHTTPPath(fd *protokit.FileDescriptor, svc *protokit.ServiceDescriptor, method *protokit.MethodDescriptor) (path string, httpMethod string) {
+	if method.Options != nil {
+		if ext, err := proto.GetExtension(method.Options, annotations.E_Http); err == nil {
+			if rule, ok := ext.(*annotations.HttpRule); ok && rule != nil {
+				switch {
+				case rule.GetGet() != "":
+					return rule.GetGet(), "GET"
+				case rule.GetPost() != "":
+					return rule.GetPost(), "POST"
+				case rule.GetPut() != "":
+					return rule.GetPut(), "PUT"
+				case rule.GetDelete() != "":
+					return rule.GetDelete(), "DELETE"
+				case rule.GetPatch() != "":
+					return rule.GetPatch(), "PATCH"
+				}
+			}
+		}
+	}
+
+	return fmt.Sprintf("/%s.%s/%s", fd.GetPackage(), svc.GetName(), method.GetName()), "POST"
+}
+
+// httpPathVariablePattern matches a google.api.http path template variable, e.g. `{name}` or `{name=shelves/*}`,
+// capturing the field name and discarding the optional `=pattern` binding.
+var httpPathVariablePattern = regexp.MustCompile(`\{([a-zA-Z0-9_.]+)(?:=[^}]*)?\}`)
+
+// openAPIPathParameters rewrites rawPath's `{name=pattern}` template variables into the plain `{name}` form
+// OpenAPI paths expect, and returns the declared path parameters alongside it so they can be attached to the
+// operation (an OpenAPI path parameter must be declared or validators reject the document).
+func openAPIPathParameters(rawPath string) (path string, params []OpenAPIParameter) {
+	matches := httpPathVariablePattern.FindAllStringSubmatchIndex(rawPath, -1)
+	if matches == nil {
+		return rawPath, nil
+	}
+
+	var resolved strings.Builder
+	params = make([]OpenAPIParameter, 0, len(matches))
+
+	last := 0
+	for _, m := range matches {
+		resolved.WriteString(rawPath[last:m[0]])
+		name := rawPath[m[2]:m[3]]
+		resolved.WriteString("{" + name + "}")
+		params = append(params, OpenAPIParameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   OpenAPISchema{Type: "string"},
+		})
+		last = m[1]
+	}
+	resolved.WriteString(rawPath[last:])
+
+	return resolved.String(), params
+}
+
+func openAPISchemaRefFor(fqMessageName string) OpenAPISchemaRef {
+	return OpenAPISchemaRef{Ref: "#/components/schemas/" + strings.TrimPrefix(fqMessageName, ".")}
+}
+
+func addOpenAPIMessageSchema(doc *OpenAPIDocument, fd *protokit.FileDescriptor, msg *protokit.Descriptor) {
+	name := fqSymbolName(fd.GetPackage(), msg.GetName())
+
+	schema := OpenAPISchema{
+		Type:        "object",
+		Description: strings.TrimSpace(msg.GetComments().GetLeading()),
+		Properties:  make(map[string]OpenAPISchema),
+	}
+
+	for _, field := range msg.GetField() {
+		schema.Properties[field.GetJsonName()] = openAPIFieldSchema(field)
+	}
+
+	doc.Components.Schemas[name] = schema
+}
+
+func addOpenAPIEnumSchema(doc *OpenAPIDocument, fd *protokit.FileDescriptor, enum *protokit.EnumDescriptor) {
+	name := fqSymbolName(fd.GetPackage(), enum.GetName())
+
+	values := make([]string, len(enum.GetValue()))
+	for i, v := range enum.GetValue() {
+		values[i] = v.GetName()
+	}
+
+	doc.Components.Schemas[name] = OpenAPISchema{Type: "string", Enum: values}
+}
+
+func openAPIFieldSchema(field *descriptor.FieldDescriptorProto) OpenAPISchema {
+	var schema OpenAPISchema
+
+	switch field.GetType().String() {
+	case "TYPE_MESSAGE", "TYPE_GROUP":
+		schema = OpenAPISchema{Ref: "#/components/schemas/" + strings.TrimPrefix(field.GetTypeName(), ".")}
+	case "TYPE_ENUM":
+		schema = OpenAPISchema{Ref: "#/components/schemas/" + strings.TrimPrefix(field.GetTypeName(), ".")}
+	case "TYPE_BOOL":
+		schema = OpenAPISchema{Type: "boolean"}
+	case "TYPE_STRING", "TYPE_BYTES":
+		schema = OpenAPISchema{Type: "string"}
+	case "TYPE_DOUBLE", "TYPE_FLOAT":
+		schema = OpenAPISchema{Type: "number"}
+	default:
+		// The remaining TYPE_* values are all integer kinds (int32/64, uint32/64, sint32/64, fixed32/64, sfixed32/64).
+		schema = OpenAPISchema{Type: "integer"}
+	}
+
+	if field.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		return OpenAPISchema{Type: "array", Items: &schema}
+	}
+	return schema
+}
+
+// defaultOpenAPITemplate marshals the document as YAML, which is the conventional OpenAPI file format.
+const defaultOpenAPITemplate = `{{ . | toYAML }}`
+
+// RenderOpenAPI builds an OpenAPIDocument from fds and renders it through customTemplate (or the default
+// YAML-dump template when customTemplate is empty), mirroring how the other renderers let TemplateFile override
+// their default template.
+func RenderOpenAPI(fds []*protokit.FileDescriptor, customTemplate string) ([]byte, error) {
+	return renderAPIDocument(BuildOpenAPIDocument(fds), customTemplate, defaultOpenAPITemplate)
+}
+
+func renderAPIDocument(doc interface{}, customTemplate, fallbackTemplate string) ([]byte, error) {
+	text := customTemplate
+	if text == "" {
+		text = fallbackTemplate
+	}
+
+	tmpl, err := template.New("api").Funcs(template.FuncMap{
+		"toYAML": func(v interface{}) (string, error) {
+			out, err := yaml.Marshal(v)
+			return string(out), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
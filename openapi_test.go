@@ -0,0 +1,109 @@
+package gendoc
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/pseudomuto/protokit"
+	annotations "google.golang.org/genproto/googleapis/api/annotations"
+)
+
+func TestOpenAPIPathParameters(t *testing.T) {
+	path, params := openAPIPathParameters("/v1/{name=shelves/*}/books/{book_id}")
+
+	if path != "/v1/{name}/books/{book_id}" {
+		t.Fatalf("path = %q, want /v1/{name}/books/{book_id}", path)
+	}
+
+	if len(params) != 2 {
+		t.Fatalf("got %d params, want 2: %+v", len(params), params)
+	}
+	if params[0].Name != "name" || params[0].In != "path" || !params[0].Required {
+		t.Errorf("params[0] = %+v, want {Name: name, In: path, Required: true}", params[0])
+	}
+	if params[1].Name != "book_id" {
+		t.Errorf("params[1].Name = %q, want book_id", params[1].Name)
+	}
+}
+
+func TestOpenAPIPathParametersNoVariables(t *testing.T) {
+	path, params := openAPIPathParameters("/v1/shelves")
+
+	if path != "/v1/shelves" {
+		t.Errorf("path = %q, want unchanged /v1/shelves", path)
+	}
+	if params != nil {
+		t.Errorf("params = %+v, want nil for a path with no variables", params)
+	}
+}
+
+// httpRuleMethod builds a MethodDescriptorProto for method with a google.api.http annotation binding it to
+// httpMethod (one of "get"/"post"/"delete") at path.
+func httpRuleMethod(name, inputType, outputType, httpMethod, path string) *descriptorpb.MethodDescriptorProto {
+	rule := &annotations.HttpRule{}
+	switch httpMethod {
+	case "get":
+		rule.Pattern = &annotations.HttpRule_Get{Get: path}
+	case "delete":
+		rule.Pattern = &annotations.HttpRule_Delete{Delete: path}
+	default:
+		rule.Pattern = &annotations.HttpRule_Post{Post: path}
+	}
+
+	opts := &descriptorpb.MethodOptions{}
+	if err := proto.SetExtension(opts, annotations.E_Http, rule); err != nil {
+		panic(err)
+	}
+
+	return &descriptorpb.MethodDescriptorProto{
+		Name:       proto.String(name),
+		InputType:  proto.String(inputType),
+		OutputType: proto.String(outputType),
+		Options:    opts,
+	}
+}
+
+func TestBuildOpenAPIDocumentSkipsRequestBodyForGet(t *testing.T) {
+	fd := &protokit.FileDescriptor{
+		FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+			Name:    proto.String("library.proto"),
+			Package: proto.String("library"),
+		},
+	}
+	svc := &protokit.ServiceDescriptor{
+		ServiceDescriptorProto: &descriptorpb.ServiceDescriptorProto{Name: proto.String("Library")},
+	}
+	svc.Methods = []*protokit.MethodDescriptor{
+		{
+			MethodDescriptorProto: httpRuleMethod("GetBook", ".library.GetBookRequest", ".library.Book", "get", "/v1/{name}"),
+			Comments:              &protokit.Comment{},
+		},
+		{
+			MethodDescriptorProto: httpRuleMethod("CreateBook", ".library.CreateBookRequest", ".library.Book", "post", "/v1/books"),
+			Comments:              &protokit.Comment{},
+		},
+	}
+	fd.Services = []*protokit.ServiceDescriptor{svc}
+
+	doc := BuildOpenAPIDocument([]*protokit.FileDescriptor{fd})
+
+	get := doc.Paths["/v1/{name}"].Get
+	if get == nil {
+		t.Fatal("expected a GET operation at /v1/{name}")
+	}
+	if get.RequestBody != nil {
+		t.Errorf("GET operation has a RequestBody, want nil")
+	}
+	if len(get.Parameters) != 1 || get.Parameters[0].Name != "name" {
+		t.Errorf("GET operation Parameters = %+v, want a single \"name\" path parameter", get.Parameters)
+	}
+
+	post := doc.Paths["/v1/books"].Post
+	if post == nil {
+		t.Fatal("expected a POST operation at /v1/books")
+	}
+	if post.RequestBody == nil {
+		t.Errorf("POST operation has no RequestBody, want one")
+	}
+}
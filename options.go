@@ -0,0 +1,440 @@
+package gendoc
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+)
+
+// Options describes how output should be generated: the renderer to use, where the template and output files
+// live, and which protos to include or exclude. It's the typed replacement for hand-splitting the `--doc_opt`
+// parameter string, modeled on the fluent `protogen.Options{...}.Run(...)` pattern used by protoc-gen-go.
+//
+// PluginOptions is an alias kept for existing callers; new code should prefer Options.
+type Options struct {
+	Type            RenderType
+	TemplateFile    string
+	OutputFile      string
+	ExcludePatterns []*regexp.Regexp
+	SourceRelative  bool
+
+	// IncludePatterns, when non-empty, is an allowlist: a file is only kept if its name matches at least one
+	// entry. It's applied alongside ExcludePatterns (exclusion wins), both at the file level.
+	IncludePatterns []*regexp.Regexp
+
+	// ExcludeSymbols and ExcludeDeprecated filter at the descriptor level, after file-level filtering: any
+	// message/enum/service/method/field whose fully-qualified name matches ExcludeSymbols, or that carries
+	// `[deprecated = true]` when ExcludeDeprecated is set, is dropped from its containing FileDescriptor.
+	ExcludeSymbols    []*regexp.Regexp
+	ExcludeDeprecated bool
+
+	// LintRulesFile is the path to a YAML/JSON rules file, set via the `rules=` doc_opt sub-parameter when
+	// Type is RenderTypeLint. LintRules holds the parsed result.
+	LintRulesFile string
+	LintRules     *LintRules
+
+	// PerService, PerMessage and PerProtoFile switch output grouping from one-file-per-directory to
+	// one-file-per-service, one-file-per-message or one-file-per-proto-file (see Grouper). At most one of the
+	// three may be set. OutputFile is always expanded as a Go text/template pattern (e.g. "{{.Service}}.md")
+	// against each Group, a literal name passing through unchanged; when any is set, ParseParameter also keeps
+	// the `out=` value as given instead of reducing it to its base name.
+	PerService   bool
+	PerMessage   bool
+	PerProtoFile bool
+
+	// RedirectsFile, when set, is the name of an extra output file containing a JSON map from each symbol's
+	// legacy anchor to its current one (see SymbolIndex.Redirects), so external links survive a grouping-mode
+	// change.
+	RedirectsFile string
+
+	// ParamFunc, when set, is called for every `key=value` pair in the parameter string that ParseParameter
+	// doesn't itself recognize, letting callers plug in their own doc_opt flags without forking this package.
+	ParamFunc func(key, value string) error
+}
+
+// Grouper returns the Grouper matching o's PerService/PerMessage/PerProtoFile/SourceRelative settings. It's an
+// error for more than one of PerService, PerMessage and PerProtoFile to be set, since each group can only be
+// named one way.
+func (o *Options) Grouper() (Grouper, error) {
+	set := 0
+	for _, v := range []bool{o.PerService, o.PerMessage, o.PerProtoFile} {
+		if v {
+			set++
+		}
+	}
+	if set > 1 {
+		return nil, fmt.Errorf("doc_opt: per_service, per_message and per_proto_file are mutually exclusive")
+	}
+
+	switch {
+	case o.PerService:
+		return ByService{}, nil
+	case o.PerMessage:
+		return ByMessage{}, nil
+	case o.PerProtoFile:
+		return ByProtoFile{}, nil
+	default:
+		return ByDirectory{SourceRelative: o.SourceRelative}, nil
+	}
+}
+
+// PluginOptions is a compatibility alias for Options, kept so existing code referencing the old name still
+// compiles.
+type PluginOptions = Options
+
+// specialRenderType maps the names of render types that don't go through NewRenderType (because they don't
+// render the NewTemplate/RenderTemplate pipeline at all: RenderTypeLint walks descriptors directly, and
+// RenderTypeOpenAPI/RenderTypeAsyncAPI build their own document). Both the `type=` grammar and the legacy
+// positional grammar check this before falling back to NewRenderType.
+func specialRenderType(name string) (RenderType, bool) {
+	switch name {
+	case "lint":
+		return RenderTypeLint, true
+	case "openapi":
+		return RenderTypeOpenAPI, true
+	case "asyncapi":
+		return RenderTypeAsyncAPI, true
+	default:
+		return RenderType(0), false
+	}
+}
+
+// NewOptions returns an Options populated with the same defaults ParseOptions has always used.
+func NewOptions() *Options {
+	return &Options{
+		Type:           RenderTypeHTML,
+		OutputFile:     "index.html",
+		SourceRelative: false,
+	}
+}
+
+// Run parses req's `--doc_opt` parameter into o (via ParseParameter, falling back to the legacy grammar), builds
+// a Plugin configured with the result, and passes it to f before generating the CodeGeneratorResponse. This is
+// the documented entrypoint for callers that want a typed Options value instead of relying on ParseOptions'
+// positional grammar.
+func (o *Options) Run(req *plugin_go.CodeGeneratorRequest, f func(*Plugin) error) (*plugin_go.CodeGeneratorResponse, error) {
+	o.applyDefaults()
+
+	if err := o.parse(req.GetParameter()); err != nil {
+		return nil, err
+	}
+
+	p := &Plugin{options: o}
+	if f != nil {
+		if err := f(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.Generate(req)
+}
+
+// applyDefaults seeds o's zero-valued Type/OutputFile fields with NewOptions' defaults, so the fluent
+// `(&Options{...}).Run(...)` usage this type's doc comment advertises works the same as constructing one via
+// NewOptions: without this, a caller who sets only, say, Type and leaves OutputFile unset would silently get
+// OutputFile == "" once Run called parse, which Group.outputName happily turns into a "." file name. Fields the
+// caller did set are left untouched.
+func (o *Options) applyDefaults() {
+	if o.Type == RenderType(0) {
+		o.Type = RenderTypeHTML
+	}
+	if o.OutputFile == "" {
+		o.OutputFile = "index.html"
+	}
+}
+
+// parse populates o from parameter, preferring the `key=value,key=value` grammar and falling back to the
+// legacy positional grammar when parameter contains no `=`.
+func (o *Options) parse(parameter string) error {
+	if parameter == "" {
+		return nil
+	}
+
+	if strings.Contains(parameter, "=") {
+		return o.ParseParameter(parameter)
+	}
+
+	return parseLegacyParameter(parameter, o)
+}
+
+// ParseParameter parses the `--doc_opt` parameter using the documented `key=value,key=value` grammar, e.g.
+// `type=markdown,out=api.md,exclude=google/.*`. It sidesteps the legacy grammar's Windows-path ambiguity around
+// `:` entirely, since pairs are split on `,` and `=`, neither of which appears in a drive letter. Unrecognized
+// keys are handed to o.ParamFunc if set, else reported as an error.
+func (o *Options) ParseParameter(parameter string) error {
+	fs := flag.NewFlagSet("doc_opt", flag.ContinueOnError)
+	fs.Usage = func() {}
+
+	var (
+		typeName          string
+		templateFile      string
+		outputFile        string
+		exclude           string
+		include           string
+		excludeSymbols    string
+		excludeDeprecated bool
+		sourceRelative    bool
+		rulesFile         string
+		perService        bool
+		perMessage        bool
+		perProtoFile      bool
+		redirectsFile     string
+		extra             = map[string]string{}
+	)
+
+	fs.StringVar(&typeName, "type", "", "renderer type or custom template file")
+	fs.StringVar(&templateFile, "template_file", "", "custom template file")
+	fs.StringVar(&outputFile, "out", "", "output file name, or output file name template when per_service/per_message/per_proto_file is set")
+	fs.StringVar(&exclude, "exclude", "", "';'-separated list of file exclude patterns")
+	fs.StringVar(&include, "include", "", "';'-separated list of file include patterns (allowlist)")
+	fs.StringVar(&excludeSymbols, "exclude_symbols", "", "';'-separated list of fully-qualified symbol exclude patterns")
+	fs.BoolVar(&excludeDeprecated, "exclude_deprecated", false, "drop symbols marked [deprecated = true]")
+	fs.BoolVar(&sourceRelative, "source_relative", false, "write output relative to each proto's source directory")
+	fs.StringVar(&rulesFile, "rules", "", "lint rules file, used when type=lint")
+	fs.BoolVar(&perService, "per_service", false, "emit one output file per service")
+	fs.BoolVar(&perMessage, "per_message", false, "emit one output file per message")
+	fs.BoolVar(&perProtoFile, "per_proto_file", false, "emit one output file per proto file")
+	fs.StringVar(&redirectsFile, "redirects", "", "output file name for the legacy-to-current anchor redirect map")
+
+	boolKeys := map[string]bool{
+		"exclude_deprecated": true,
+		"source_relative":    true,
+		"per_service":        true,
+		"per_message":        true,
+		"per_proto_file":     true,
+	}
+
+	args := make([]string, 0)
+	for _, pair := range strings.Split(parameter, ",") {
+		if pair == "" {
+			continue
+		}
+
+		key, value := pair, "true"
+		if idx := strings.Index(pair, "="); idx >= 0 {
+			key, value = pair[:idx], pair[idx+1:]
+		}
+
+		switch key {
+		case "type", "template_file", "out", "exclude", "include", "exclude_symbols", "exclude_deprecated",
+			"source_relative", "rules", "per_service", "per_message", "per_proto_file", "redirects":
+			if boolKeys[key] {
+				// flag.FlagSet bool flags don't consume a following arg, so "-key", "value" would leave
+				// "value" as the first non-flag argument and stop parsing there; "-key=value" is one token.
+				args = append(args, "-"+key+"="+value)
+			} else {
+				args = append(args, "-"+key, value)
+			}
+		default:
+			extra[key] = value
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("invalid doc_opt parameter: %s", parameter)
+	}
+
+	if renderType, ok := specialRenderType(typeName); ok {
+		o.Type = renderType
+	} else if typeName != "" {
+		renderType, err := NewRenderType(typeName)
+		if err != nil {
+			o.TemplateFile = typeName
+		} else {
+			o.Type = renderType
+		}
+	}
+	if templateFile != "" {
+		o.TemplateFile = templateFile
+	}
+	o.PerService = perService
+	o.PerMessage = perMessage
+	o.PerProtoFile = perProtoFile
+	if outputFile != "" {
+		if perService || perMessage || perProtoFile {
+			o.OutputFile = outputFile
+		} else {
+			o.OutputFile = path.Base(outputFile)
+		}
+	}
+	if exclude != "" {
+		patterns, err := compilePatterns(exclude)
+		if err != nil {
+			return err
+		}
+		o.ExcludePatterns = append(o.ExcludePatterns, patterns...)
+	}
+	if include != "" {
+		patterns, err := compilePatterns(include)
+		if err != nil {
+			return err
+		}
+		o.IncludePatterns = append(o.IncludePatterns, patterns...)
+	}
+	if excludeSymbols != "" {
+		patterns, err := compilePatterns(excludeSymbols)
+		if err != nil {
+			return err
+		}
+		o.ExcludeSymbols = append(o.ExcludeSymbols, patterns...)
+	}
+	o.ExcludeDeprecated = excludeDeprecated
+	o.SourceRelative = sourceRelative
+
+	if rulesFile != "" {
+		o.LintRulesFile = rulesFile
+		rules, err := LoadLintRules(rulesFile)
+		if err != nil {
+			return err
+		}
+		o.LintRules = rules
+	}
+
+	if redirectsFile != "" {
+		o.RedirectsFile = redirectsFile
+	}
+
+	for key, value := range extra {
+		if o.ParamFunc == nil {
+			return fmt.Errorf("unknown doc_opt parameter: %s", key)
+		}
+		if err := o.ParamFunc(key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compilePatterns compiles a ';'-separated list of regular expressions.
+func compilePatterns(list string) ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0)
+	for _, pattern := range strings.Split(list, ";") {
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, r)
+	}
+	return patterns, nil
+}
+
+// parseLegacyParameter implements the original ParseOptions grammar:
+// <TYPE|TEMPLATE_FILE>,<OUTPUT_FILE>[,default|source_relative]:<EXCLUDE_PATTERN>,<EXCLUDE_PATTERN>*
+//
+// It's kept byte-for-byte compatible (Windows-path heuristics included) so existing invocations of protoc
+// using the comma/colon form keep working unchanged.
+func parseLegacyParameter(params string, options *Options) error {
+	if strings.Contains(params, ":") {
+		// Parse out exclude patterns if any
+		parts := strings.Split(params, ":")
+
+		// On Windows, there can legitimately be up to two ":" in the first part: one for each filename in case absolute paths
+		// are used, as a divider between the drive letter and the remainder of the path. That makes it really ugly: we now
+		// need to do some heuristics to "match" Windows path patterns to detect if a : is not to be treated as a divider
+		// between the first parameter half and the second half with the exclude patterns.
+		// This fixes GitHub issue #497.
+		winPathHeuristic := func(first, second string) bool {
+			// A Windows path in our doc_opt parameter is assumed to have a backslash in the second part...
+			if strings.HasPrefix(second, "\\") {
+				// ...and a drive letter either directly at the start of the first part or being preceded by a comma.
+				// If both of these conditions match, it is assumed that the : separation actually splitted a Windows
+				// path in two parts which belong together.
+				firstMatches, _ := regexp.MatchString("(?:^|,)[a-zA-z]$", first)
+				return firstMatches
+			}
+			return false
+		}
+		excludePart := ""
+		if winPathHeuristic(parts[0], parts[1]) {
+			params = parts[0] + ":" + parts[1]
+			if len(parts) > 2 {
+				if winPathHeuristic(params, parts[2]) {
+					params = params + ":" + parts[2]
+					if len(parts) > 3 {
+						excludePart = parts[3]
+					}
+				} else {
+					excludePart = parts[2]
+				}
+			}
+		} else {
+			params = parts[0]
+			excludePart = parts[1]
+		}
+
+		if len(excludePart) > 0 {
+			for _, pattern := range strings.Split(excludePart, ",") {
+				r, err := regexp.Compile(pattern)
+				if err != nil {
+					return err
+				}
+				options.ExcludePatterns = append(options.ExcludePatterns, r)
+			}
+		}
+	}
+	if params == "" {
+		return nil
+	}
+
+	if !strings.Contains(params, ",") {
+		return fmt.Errorf("Invalid parameter: %s", params)
+	}
+
+	parts := strings.Split(params, ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return fmt.Errorf("Invalid parameter: %s", params)
+	}
+
+	options.TemplateFile = parts[0]
+	options.OutputFile = path.Base(parts[1])
+	if len(parts) > 2 {
+		switch parts[2] {
+		case "source_relative":
+			options.SourceRelative = true
+		case "default":
+			options.SourceRelative = false
+		default:
+			return fmt.Errorf("Invalid parameter: %s", params)
+		}
+	}
+	options.SourceRelative = len(parts) > 2 && parts[2] == "source_relative"
+
+	if renderType, ok := specialRenderType(options.TemplateFile); ok {
+		options.Type = renderType
+		options.TemplateFile = ""
+	} else if renderType, err := NewRenderType(options.TemplateFile); err == nil {
+		options.Type = renderType
+		options.TemplateFile = ""
+	}
+
+	if options.Type == RenderTypeLint {
+		// For lint mode, the second positional slot names the rules file rather than an output file.
+		options.LintRulesFile = parts[1]
+		options.OutputFile = ""
+
+		rules, err := LoadLintRules(options.LintRulesFile)
+		if err != nil {
+			return err
+		}
+		options.LintRules = rules
+	}
+
+	return nil
+}
+
+// ParseOptions parses plugin options from a CodeGeneratorRequest. New callers should prefer constructing an
+// Options directly (see NewOptions and Options.Run); this function remains for existing callers and dispatches
+// to the new `key=value` grammar automatically when the parameter contains an `=`, falling back to the legacy
+// positional grammar otherwise.
+func ParseOptions(req *plugin_go.CodeGeneratorRequest) (*Options, error) {
+	options := NewOptions()
+	if err := options.parse(req.GetParameter()); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
@@ -0,0 +1,77 @@
+package gendoc
+
+import "testing"
+
+func TestApplyDefaultsSeedsBareLiteral(t *testing.T) {
+	o := &Options{}
+	o.applyDefaults()
+
+	if o.Type != RenderTypeHTML {
+		t.Errorf("Type = %v, want RenderTypeHTML", o.Type)
+	}
+	if o.OutputFile != "index.html" {
+		t.Errorf("OutputFile = %q, want %q", o.OutputFile, "index.html")
+	}
+}
+
+func TestApplyDefaultsLeavesExplicitFieldsAlone(t *testing.T) {
+	o := &Options{Type: RenderType(77), OutputFile: "custom.out"}
+	o.applyDefaults()
+
+	if o.Type != RenderType(77) {
+		t.Errorf("Type = %v, want untouched RenderType(77)", o.Type)
+	}
+	if o.OutputFile != "custom.out" {
+		t.Errorf("OutputFile = %q, want untouched %q", o.OutputFile, "custom.out")
+	}
+}
+
+func TestOptionsGrouperMutualExclusivity(t *testing.T) {
+	cases := []struct {
+		name string
+		o    Options
+		want Grouper
+	}{
+		{"none", Options{}, ByDirectory{}},
+		{"per_service", Options{PerService: true}, ByService{}},
+		{"per_message", Options{PerMessage: true}, ByMessage{}},
+		{"per_proto_file", Options{PerProtoFile: true}, ByProtoFile{}},
+	}
+
+	for _, c := range cases {
+		got, err := c.o.Grouper()
+		if err != nil {
+			t.Errorf("%s: Grouper() error = %v", c.name, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: Grouper() = %#v, want %#v", c.name, got, c.want)
+		}
+	}
+
+	conflicting := []Options{
+		{PerService: true, PerMessage: true},
+		{PerService: true, PerProtoFile: true},
+		{PerMessage: true, PerProtoFile: true},
+		{PerService: true, PerMessage: true, PerProtoFile: true},
+	}
+	for _, o := range conflicting {
+		if _, err := o.Grouper(); err == nil {
+			t.Errorf("Grouper() on %+v = nil error, want a mutual-exclusivity error", o)
+		}
+	}
+}
+
+func TestParseParameterBoolFlagsDontConsumeFollowingValue(t *testing.T) {
+	o := &Options{}
+	if err := o.ParseParameter("per_service=true,out={{.Service}}.md"); err != nil {
+		t.Fatalf("ParseParameter: %v", err)
+	}
+
+	if !o.PerService {
+		t.Errorf("PerService = false, want true")
+	}
+	if o.OutputFile != "{{.Service}}.md" {
+		t.Errorf("OutputFile = %q, want %q (per_service's bool token must not have swallowed it)", o.OutputFile, "{{.Service}}.md")
+	}
+}